@@ -0,0 +1,103 @@
+/*
+ * Copyright 2022 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podfingerprint
+
+// VersionAttributes identifies the format of a fingerprint produced by a
+// Fingerprint constructed with NewFingerprintWithAttributes and a
+// non-default AttributeExtractor. It is distinct from Version so that
+// Check rejects a comparison between a plain, namespace/name-only
+// fingerprint and one that also folds in extra pod attributes, rather
+// than silently treating two different things as comparable.
+// IsVersionCompatible reports both Version and VersionAttributes as
+// compatible.
+const VersionAttributes = "v002"
+
+// PodAttributes holds the additional, optional fields an
+// AttributeExtractor can contribute to a fingerprint beyond namespace
+// and name. Every field is already canonicalized into a stable string
+// by the extractor; the empty string means "not reported" and is
+// omitted from the hash.
+type PodAttributes struct {
+	// UID is the pod's unique identifier, so a deleted and recreated
+	// pod that reuses a namespace/name produces a different signature.
+	UID string
+	// ContainerDigest is a caller-supplied, canonicalized digest of the
+	// pod's container specs (e.g. images and resource requests per
+	// container).
+	ContainerDigest string
+	// QoSClass is the pod's QoS class (e.g. "Guaranteed", "Burstable",
+	// "BestEffort").
+	QoSClass string
+	// Requests is a caller-supplied, canonicalized digest of the pod's
+	// aggregate resource requests.
+	Requests string
+}
+
+// AttributeExtractor reports the PodAttributes to fold into a
+// fingerprint for a given pod, beyond its namespace and name.
+// Implementations must be deterministic: calling Attributes twice with
+// an unchanged pod must return the same PodAttributes.
+type AttributeExtractor interface {
+	Attributes(pod PodIdentifier) PodAttributes
+}
+
+type defaultAttributeExtractor struct{}
+
+func (defaultAttributeExtractor) Attributes(pod PodIdentifier) PodAttributes {
+	return PodAttributes{}
+}
+
+// DefaultAttributeExtractor returns the AttributeExtractor used
+// implicitly by NewFingerprint: it reports no attributes beyond
+// namespace/name, preserving today's behavior and Version.
+func DefaultAttributeExtractor() AttributeExtractor {
+	return defaultAttributeExtractor{}
+}
+
+func isDefaultAttributeExtractor(extractor AttributeExtractor) bool {
+	if extractor == nil {
+		return true
+	}
+	_, ok := extractor.(defaultAttributeExtractor)
+	return ok
+}
+
+// UIDPodIdentifier is implemented by pods which can additionally report
+// their UID. UIDAttributeExtractor uses it when available.
+type UIDPodIdentifier interface {
+	PodIdentifier
+	GetUID() string
+}
+
+type uidAttributeExtractor struct{}
+
+func (uidAttributeExtractor) Attributes(pod PodIdentifier) PodAttributes {
+	uidPod, ok := pod.(UIDPodIdentifier)
+	if !ok {
+		return PodAttributes{}
+	}
+	return PodAttributes{UID: uidPod.GetUID()}
+}
+
+// UIDAttributeExtractor additionally folds each pod's UID into the
+// fingerprint, so a deleted and recreated pod that reuses the same
+// namespace/name no longer produces an identical signature. Pods that
+// do not implement UIDPodIdentifier contribute no UID, same as with
+// DefaultAttributeExtractor.
+func UIDAttributeExtractor() AttributeExtractor {
+	return uidAttributeExtractor{}
+}
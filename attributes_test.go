@@ -0,0 +1,81 @@
+/*
+ * Copyright 2022 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podfingerprint
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type uidPodIdent struct {
+	podIdent
+	UID string
+}
+
+func (pi uidPodIdent) GetUID() string {
+	return pi.UID
+}
+
+func TestNewFingerprintWithDefaultAttributesMatchesPlain(t *testing.T) {
+	pod := uidPodIdent{podIdent: podIdent{Namespace: "ns1", Name: "pod1"}, UID: "uid-1"}
+
+	fp := NewFingerprint(1)
+	fp.AddPod(pod)
+
+	fpAttrs := NewFingerprintWithAttributes(1, DefaultAttributeExtractor())
+	fpAttrs.AddPod(pod)
+
+	if got, want := fpAttrs.Sign(), fp.Sign(); got != want {
+		t.Fatalf("default extractor changed the signature: got %q expected %q", got, want)
+	}
+	if !strings.Contains(fp.Sign(), Version) {
+		t.Fatalf("plain fingerprint %q does not carry Version %q", fp.Sign(), Version)
+	}
+}
+
+func TestUIDAttributeExtractorChangesSignatureAndVersion(t *testing.T) {
+	podA := uidPodIdent{podIdent: podIdent{Namespace: "ns1", Name: "pod1"}, UID: "uid-a"}
+	podB := uidPodIdent{podIdent: podIdent{Namespace: "ns1", Name: "pod1"}, UID: "uid-b"}
+
+	fpA := NewFingerprintWithAttributes(1, UIDAttributeExtractor())
+	fpA.AddPod(podA)
+	fpB := NewFingerprintWithAttributes(1, UIDAttributeExtractor())
+	fpB.AddPod(podB)
+
+	if fpA.Sign() == fpB.Sign() {
+		t.Fatalf("pods with different UIDs produced the same signature %q", fpA.Sign())
+	}
+	if !strings.Contains(fpA.Sign(), VersionAttributes) {
+		t.Errorf("signature %q does not carry VersionAttributes %q", fpA.Sign(), VersionAttributes)
+	}
+}
+
+func TestCheckRejectsCrossVersionComparison(t *testing.T) {
+	pod := uidPodIdent{podIdent: podIdent{Namespace: "ns1", Name: "pod1"}, UID: "uid-1"}
+
+	plain := NewFingerprint(1)
+	plain.AddPod(pod)
+
+	withAttrs := NewFingerprintWithAttributes(1, UIDAttributeExtractor())
+	withAttrs.AddPod(pod)
+
+	err := plain.Check(withAttrs.Sign())
+	if !errors.Is(err, ErrIncompatibleVersion) {
+		t.Fatalf("checking a VersionAttributes signature against a plain Fingerprint: got %v expected %v", err, ErrIncompatibleVersion)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2022 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podfingerprint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveIsInverseOfAdd(t *testing.T) {
+	fp := NewFingerprint(0)
+	fp.Add("ns1", "pod1")
+	fp.Add("ns1", "pod2")
+	empty := NewFingerprint(0)
+
+	fp.Remove("ns1", "pod1")
+	fp.Remove("ns1", "pod2")
+
+	if !reflect.DeepEqual(fp.Sum(), empty.Sum()) {
+		t.Fatalf("fingerprint after adding and removing the same pods got %x expected the empty signature %x", fp.Sum(), empty.Sum())
+	}
+}
+
+func TestRemoveOrderIndependent(t *testing.T) {
+	fpA := NewFingerprint(0)
+	fpA.Add("ns1", "pod1")
+	fpA.Add("ns1", "pod2")
+	fpA.Add("ns1", "pod3")
+	fpA.Remove("ns1", "pod2")
+
+	fpB := NewFingerprint(0)
+	fpB.Add("ns1", "pod2")
+	fpB.Add("ns1", "pod1")
+	fpB.Remove("ns1", "pod2")
+	fpB.Add("ns1", "pod3")
+
+	if got, want := fpA.Sign(), fpB.Sign(); got != want {
+		t.Fatalf("signature depends on add/remove order: got %q expected %q", got, want)
+	}
+}
+
+func TestRemoveUnknownPodIsNoop(t *testing.T) {
+	fp := NewFingerprint(0)
+	fp.Add("ns1", "pod1")
+	before := fp.Sign()
+
+	fp.Remove("ns2", "does-not-exist")
+
+	if got := fp.Sign(); got != before {
+		t.Fatalf("removing a pod that was never added changed the signature: got %q expected %q", got, before)
+	}
+}
+
+func TestMergeMatchesAddingBothDirectly(t *testing.T) {
+	fpNodeA := NewFingerprint(0)
+	fpNodeA.Add("ns1", "pod1")
+	fpNodeA.Add("ns1", "pod2")
+
+	fpNodeB := NewFingerprint(0)
+	fpNodeB.Add("ns2", "pod3")
+	fpNodeB.Add("ns2", "pod4")
+
+	cluster := NewFingerprint(0)
+	if err := cluster.Merge(fpNodeA); err != nil {
+		t.Fatalf("unexpected error merging node A: %v", err)
+	}
+	if err := cluster.Merge(fpNodeB); err != nil {
+		t.Fatalf("unexpected error merging node B: %v", err)
+	}
+
+	direct := NewFingerprint(0)
+	direct.Add("ns1", "pod1")
+	direct.Add("ns1", "pod2")
+	direct.Add("ns2", "pod3")
+	direct.Add("ns2", "pod4")
+
+	if got, want := cluster.Sign(), direct.Sign(); got != want {
+		t.Fatalf("merged cluster signature %q differs from adding every pod directly %q", got, want)
+	}
+}
+
+func TestMergeRejectsMismatchedVersions(t *testing.T) {
+	plain := NewFingerprint(0)
+	withAttrs := NewFingerprintWithAttributes(0, UIDAttributeExtractor())
+
+	if err := plain.Merge(withAttrs); err == nil {
+		t.Fatalf("expected an error merging fingerprints of different versions")
+	}
+}
+
+func TestMergeNil(t *testing.T) {
+	fp := NewFingerprint(0)
+	if err := fp.Merge(nil); err == nil {
+		t.Fatalf("expected an error merging a nil fingerprint")
+	}
+}
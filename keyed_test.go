@@ -0,0 +1,104 @@
+/*
+ * Copyright 2022 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podfingerprint
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeyedFingerprintDiffersFromPlain(t *testing.T) {
+	plain := NewFingerprint(1)
+	plain.Add("ns1", "pod1")
+
+	keyed := NewKeyedFingerprint(1, []byte("s3cr3t"))
+	keyed.Add("ns1", "pod1")
+
+	if plain.Sign() == keyed.Sign() {
+		t.Fatalf("keyed and plain fingerprints produced the same signature %q", plain.Sign())
+	}
+	if !strings.HasPrefix(keyed.Sign(), "pfp1") {
+		t.Errorf("keyed signature %q does not carry the keyed prefix", keyed.Sign())
+	}
+}
+
+func TestKeyedFingerprintWithEmptyKeyStaysKeyed(t *testing.T) {
+	plain := NewFingerprint(1)
+	plain.Add("ns1", "pod1")
+
+	keyedEmpty := NewKeyedFingerprint(1, []byte{})
+	keyedEmpty.Add("ns1", "pod1")
+
+	keyedNil := NewKeyedFingerprint(1, nil)
+	keyedNil.Add("ns1", "pod1")
+
+	if got, want := keyedEmpty.Sign()[len(prefixPlain):], plain.Sign()[len(prefixPlain):]; got == want {
+		t.Fatalf("an empty key produced a body equal to the unkeyed hash: %q", got)
+	}
+	if got, want := keyedNil.Sign(), keyedEmpty.Sign(); got != want {
+		t.Fatalf("a nil key and an empty key disagreed: %q vs %q", got, want)
+	}
+
+	forger := NewFingerprint(1)
+	forger.Add("ns1", "pod1")
+	if err := keyedEmpty.Check(forger.Sign()); err == nil {
+		t.Fatalf("an empty-keyed fingerprint accepted a plain, unkeyed signature as valid")
+	}
+}
+
+func TestKeyedFingerprintRequiresMatchingKey(t *testing.T) {
+	signer := NewKeyedFingerprint(1, []byte("s3cr3t"))
+	signer.Add("ns1", "pod1")
+	signature := signer.Sign()
+
+	verifierRightKey := NewKeyedFingerprint(1, []byte("s3cr3t"))
+	verifierRightKey.Add("ns1", "pod1")
+	if err := verifierRightKey.Check(signature); err != nil {
+		t.Errorf("unexpected error checking with the right key: %v", err)
+	}
+
+	verifierWrongKey := NewKeyedFingerprint(1, []byte("wrong"))
+	verifierWrongKey.Add("ns1", "pod1")
+	if err := verifierWrongKey.Check(signature); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("checking with the wrong key: got %v expected %v", err, ErrSignatureMismatch)
+	}
+}
+
+func TestCheckRejectsCrossSchemeComparison(t *testing.T) {
+	keyed := NewKeyedFingerprint(1, []byte("s3cr3t"))
+	keyed.Add("ns1", "pod1")
+
+	plain := NewFingerprint(1)
+	plain.Add("ns1", "pod1")
+
+	if err := plain.Check(keyed.Sign()); !errors.Is(err, ErrIncompatibleVersion) {
+		t.Errorf("checking a keyed signature with a plain fingerprint: got %v expected %v", err, ErrIncompatibleVersion)
+	}
+	if err := keyed.Check(plain.Sign()); !errors.Is(err, ErrIncompatibleVersion) {
+		t.Errorf("checking a plain signature with a keyed fingerprint: got %v expected %v", err, ErrIncompatibleVersion)
+	}
+}
+
+func TestMergeRejectsMismatchedKeyScheme(t *testing.T) {
+	plain := NewFingerprint(0)
+	keyed := NewKeyedFingerprint(0, []byte("s3cr3t"))
+
+	if err := plain.Merge(keyed); err == nil {
+		t.Fatalf("expected an error merging a plain fingerprint with a keyed one")
+	}
+}
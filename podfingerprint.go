@@ -0,0 +1,359 @@
+/*
+ * Copyright 2022 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package podfingerprint computes a stable, order-independent signature
+// over a set of pods running on a node. Two nodes whose pod sets agree
+// will always produce the same signature, regardless of the order in
+// which the pods were observed; nodes whose pod sets differ will, with
+// overwhelming probability, produce different signatures.
+package podfingerprint
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+const (
+	// prefixPlain identifies the payload of an unkeyed fingerprint
+	// string as coming from this package, as opposed to an unrelated or
+	// malformed string.
+	prefixPlain = "pfp0"
+	// prefixKeyed identifies a fingerprint produced by a Fingerprint
+	// built with NewKeyedFingerprint. It is distinct from prefixPlain
+	// so a keyed and an unkeyed fingerprint are never mistaken for one
+	// another, even when they happen to describe the same pods.
+	prefixKeyed = "pfp1"
+	// Version identifies the format of the fingerprint payload. It must
+	// be bumped whenever the computation changes in a way that makes
+	// fingerprints produced by different versions not comparable.
+	Version = "v001"
+
+	sumSize = 8 // bytes
+)
+
+var (
+	// ErrMalformed is returned when a fingerprint string cannot be
+	// parsed at all, e.g. because it is too short or carries an
+	// unrecognized prefix.
+	ErrMalformed = errors.New("malformed fingerprint")
+	// ErrIncompatibleVersion is returned when a fingerprint string is
+	// well-formed but was produced by a version of this package which
+	// is not compatible with the running one.
+	ErrIncompatibleVersion = errors.New("incompatible fingerprint version")
+	// ErrSignatureMismatch is returned by Check when the fingerprint
+	// computed from the observed pods does not match the supplied one.
+	ErrSignatureMismatch = errors.New("fingerprint signature mismatch")
+)
+
+// PodIdentifier is the minimal amount of information this package needs
+// about a pod in order to fingerprint it. Callers typically implement
+// this directly on top of their own pod type to avoid copying data.
+type PodIdentifier interface {
+	GetNamespace() string
+	GetName() string
+}
+
+// Fingerprint accumulates pod identities and computes a signature over
+// them. Internally it keeps a count per distinct identity rather than a
+// plain list, so Add and Remove are cheap and each other's inverse, and
+// the order of Add calls does not matter. A count never goes below zero:
+// a Remove of an identity not currently recorded is a no-op, so it does
+// not cancel a later Add of that identity.
+// The zero value is ready to use, behaves like one returned by
+// NewFingerprint, and only ever considers namespace/name.
+type Fingerprint struct {
+	counts    map[string]int
+	extractor AttributeExtractor
+	version   string
+	sigPrefix string
+	key       []byte
+}
+
+// NewFingerprint returns a Fingerprint ready to accept up to size pod
+// identities without reallocating. size is only a hint; the Fingerprint
+// still grows as needed if more pods are added. The returned Fingerprint
+// only considers namespace/name, matching every fingerprint produced by
+// this package before AttributeExtractor existed; use
+// NewFingerprintWithAttributes to fold in more.
+func NewFingerprint(size int) *Fingerprint {
+	return &Fingerprint{
+		counts:  make(map[string]int, size),
+		version: Version,
+	}
+}
+
+// NewKeyedFingerprint returns a Fingerprint like NewFingerprint, but one
+// that signs with HMAC-SHA256 under key instead of a plain hash. Holding
+// key is what lets Check later tell a fingerprint produced by a trusted
+// party apart from one a node simply claims to be correct: without key,
+// anyone observing a signature could forge one for an arbitrary pod set.
+// Keyed fingerprints sign under prefixKeyed, so Check rejects
+// comparisons against plain, unkeyed ones. IsVersionCompatible only sees
+// the version component, which keyed and plain fingerprints share, so it
+// does not tell them apart.
+//
+// Whether a Fingerprint is keyed is decided by sigPrefix alone, not by
+// whether key happens to be empty: an all-zero-length key is a caller
+// mistake, not a request to fall back to an unauthenticated, plain
+// signature, so Sum and Check keep using HMAC in that case too.
+func NewKeyedFingerprint(size int, key []byte) *Fingerprint {
+	fp := NewFingerprint(size)
+	fp.sigPrefix = prefixKeyed
+	fp.key = make([]byte, len(key))
+	copy(fp.key, key)
+	return fp
+}
+
+func (fp *Fingerprint) ensureCounts() {
+	if fp.counts == nil {
+		fp.counts = make(map[string]int)
+	}
+}
+
+// NewFingerprintWithAttributes returns a Fingerprint like NewFingerprint,
+// but one that additionally folds into the hash whatever attributes
+// extractor reports for each pod passed to AddPod. Passing
+// DefaultAttributeExtractor is equivalent to calling NewFingerprint.
+// Any other extractor moves the produced signatures to VersionAttributes,
+// so Check rejects comparisons against plain, namespace/name-only
+// fingerprints with ErrIncompatibleVersion instead of silently
+// mismatching. IsVersionCompatible reports both Version and
+// VersionAttributes as compatible.
+func NewFingerprintWithAttributes(size int, extractor AttributeExtractor) *Fingerprint {
+	fp := NewFingerprint(size)
+	fp.extractor = extractor
+	if !isDefaultAttributeExtractor(extractor) {
+		fp.version = VersionAttributes
+	}
+	return fp
+}
+
+// AddPod records the identity of pod in the fingerprint, along with
+// whatever attributes the Fingerprint's AttributeExtractor, if any,
+// reports for it.
+func (fp *Fingerprint) AddPod(pod PodIdentifier) {
+	var attrs PodAttributes
+	if fp.extractor != nil {
+		attrs = fp.extractor.Attributes(pod)
+	}
+	fp.addKey(identKeyWithAttributes(pod.GetNamespace(), pod.GetName(), attrs))
+}
+
+// Add records the identity (namespace, name) in the fingerprint. Add has
+// no pod value to hand to an AttributeExtractor, so it never contributes
+// extra attributes even on a Fingerprint created with
+// NewFingerprintWithAttributes; use AddPod for that.
+func (fp *Fingerprint) Add(namespace, name string) {
+	fp.addKey(identKey(namespace, name))
+}
+
+func (fp *Fingerprint) addKey(key string) {
+	fp.ensureCounts()
+	fp.counts[key]++
+}
+
+// RemovePod undoes a prior AddPod call for pod: it is the exact inverse
+// of AddPod, so Sum is the same whatever order the AddPod calls, and the
+// RemovePod calls that follow them, happen in. Removing a pod that is not
+// currently recorded, or removing it more times than it was added, is a
+// no-op and does not cancel a later AddPod of that pod.
+func (fp *Fingerprint) RemovePod(pod PodIdentifier) {
+	var attrs PodAttributes
+	if fp.extractor != nil {
+		attrs = fp.extractor.Attributes(pod)
+	}
+	fp.removeKey(identKeyWithAttributes(pod.GetNamespace(), pod.GetName(), attrs))
+}
+
+// Remove undoes a prior Add call for (namespace, name); see RemovePod.
+func (fp *Fingerprint) Remove(namespace, name string) {
+	fp.removeKey(identKey(namespace, name))
+}
+
+func (fp *Fingerprint) removeKey(key string) {
+	fp.ensureCounts()
+	count, ok := fp.counts[key]
+	if !ok {
+		return
+	}
+	if count <= 1 {
+		delete(fp.counts, key)
+		return
+	}
+	fp.counts[key] = count - 1
+}
+
+// Merge folds every identity recorded in other into fp, as if every pod
+// currently recorded in other, net of removals, had instead been added
+// directly to fp. It lets a
+// cluster-level Fingerprint be rolled up from per-node ones without
+// re-adding every pod identity. Merge returns an error if fp and other
+// were not built with the same version, e.g. one uses an
+// AttributeExtractor the other doesn't.
+func (fp *Fingerprint) Merge(other *Fingerprint) error {
+	if other == nil {
+		return fmt.Errorf("cannot merge a nil fingerprint")
+	}
+	if fp.sigPrefixTag() != other.sigPrefixTag() {
+		return fmt.Errorf("cannot merge fingerprints signed under different schemes: %s vs %s", fp.sigPrefixTag(), other.sigPrefixTag())
+	}
+	if fp.signVersion() != other.signVersion() {
+		return fmt.Errorf("cannot merge fingerprints of different versions: %s vs %s", fp.signVersion(), other.signVersion())
+	}
+	fp.ensureCounts()
+	for key, count := range other.counts {
+		fp.counts[key] += count
+	}
+	return nil
+}
+
+// Sum returns the raw signature bytes computed so far. Sum is stable
+// with respect to the order in which pods were added and removed, and
+// only depends on the net count of each distinct identity. If fp was
+// built with NewKeyedFingerprint, Sum is an HMAC-SHA256 under that key
+// rather than a plain hash.
+//
+// Sum always recomputes from every identity currently recorded: Add,
+// Remove and Merge only amortize the cost of keeping the pod set up to
+// date, not of hashing it. Call Sum once per reconcile cycle, not once
+// per Add/Remove, if that cost matters.
+func (fp *Fingerprint) Sum() []byte {
+	sorted := make([]string, 0, len(fp.counts))
+	for key, count := range fp.counts {
+		for i := 0; i < count; i++ {
+			sorted = append(sorted, key)
+		}
+	}
+	sort.Strings(sorted)
+
+	var h hash.Hash
+	if fp.sigPrefixTag() == prefixKeyed {
+		h = hmac.New(sha256.New, fp.key)
+	} else {
+		h = sha256.New()
+	}
+	for _, ident := range sorted {
+		fmt.Fprintln(h, ident)
+	}
+	return h.Sum(nil)[:sumSize]
+}
+
+// Sign returns the textual, versioned fingerprint computed so far. The
+// returned string is what callers should persist or exchange, and is
+// what Check expects to receive back.
+func (fp *Fingerprint) Sign() string {
+	return fp.sigPrefixTag() + fp.signVersion() + hex.EncodeToString(fp.Sum())
+}
+
+// signVersion returns the version tag this Fingerprint signs with. A
+// zero value Fingerprint, like one returned by NewFingerprint, signs
+// with Version.
+func (fp *Fingerprint) signVersion() string {
+	if fp.version == "" {
+		return Version
+	}
+	return fp.version
+}
+
+// sigPrefixTag returns the prefix this Fingerprint signs with. A zero
+// value Fingerprint, like one returned by NewFingerprint, signs with
+// prefixPlain.
+func (fp *Fingerprint) sigPrefixTag() string {
+	if fp.sigPrefix == "" {
+		return prefixPlain
+	}
+	return fp.sigPrefix
+}
+
+// Check compares fingerprint against the signature computed from the
+// pods recorded so far, returning nil if they match. It returns
+// ErrMalformed if fingerprint cannot be parsed, ErrIncompatibleVersion
+// if it was produced by an incompatible version, or a different signing
+// scheme (plain vs. keyed), of this package, and ErrSignatureMismatch if
+// the pod sets disagree. If fp was built with NewKeyedFingerprint, the
+// comparison against fingerprint runs in constant time.
+func (fp *Fingerprint) Check(fingerprint string) error {
+	prefixLen := len(prefixPlain)
+	minLen := prefixLen + len(Version)
+	if len(fingerprint) < minLen {
+		return ErrMalformed
+	}
+	gotPrefix := fingerprint[:prefixLen]
+	if gotPrefix != prefixPlain && gotPrefix != prefixKeyed {
+		return ErrMalformed
+	}
+	if gotPrefix != fp.sigPrefixTag() {
+		return ErrIncompatibleVersion
+	}
+
+	version := fingerprint[prefixLen:minLen]
+	compat, err := IsVersionCompatible(version)
+	if err != nil {
+		return err
+	}
+	if !compat || version != fp.signVersion() {
+		return ErrIncompatibleVersion
+	}
+
+	want := fp.Sign()
+	if fp.sigPrefixTag() == prefixKeyed {
+		if !hmac.Equal([]byte(want), []byte(fingerprint)) {
+			return fmt.Errorf("%w: got %q expected %q", ErrSignatureMismatch, fingerprint, want)
+		}
+		return nil
+	}
+	if want != fingerprint {
+		return fmt.Errorf("%w: got %q expected %q", ErrSignatureMismatch, fingerprint, want)
+	}
+	return nil
+}
+
+// IsVersionCompatible reports whether version, the version component of
+// a fingerprint string, can be compared against fingerprints produced
+// by the running version of this package. It returns ErrMalformed if
+// version does not have the expected length.
+func IsVersionCompatible(version string) (bool, error) {
+	if len(version) != len(Version) {
+		return false, ErrMalformed
+	}
+	return version == Version || version == VersionAttributes, nil
+}
+
+func identKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func identKeyWithAttributes(namespace, name string, attrs PodAttributes) string {
+	key := identKey(namespace, name)
+	if attrs.UID != "" {
+		key += "/uid=" + attrs.UID
+	}
+	if attrs.ContainerDigest != "" {
+		key += "/containers=" + attrs.ContainerDigest
+	}
+	if attrs.QoSClass != "" {
+		key += "/qos=" + attrs.QoSClass
+	}
+	if attrs.Requests != "" {
+		key += "/requests=" + attrs.Requests
+	}
+	return key
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2022 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podfingerprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// PodIdent is the (namespace, name) identity of a single pod, exported
+// so callers can inspect the outcome of Diff and Compare without
+// depending on their own pod type.
+type PodIdent struct {
+	Namespace string
+	Name      string
+}
+
+// Status is a point-in-time, human-readable snapshot of the pods
+// observed by a TracingFingerprint. Unlike the plain signature returned
+// by Sign, a Status can be persisted and later compared against another
+// one to explain why two signatures disagree.
+type Status struct {
+	Version   string
+	Signature string
+	Pods      []PodIdent
+}
+
+// TracingFingerprint behaves exactly like Fingerprint, but additionally
+// remembers every pod identity it observes. Use it instead of
+// Fingerprint when a mismatch should be debuggable, e.g. on the kubelet
+// side where fingerprints are produced; the plain Fingerprint remains
+// the cheaper choice for call sites that only ever need to compare
+// signatures.
+//
+// Merge is Fingerprint.Merge: it folds the other Fingerprint into the
+// signature only, and the merged pods do not appear in Status.
+type TracingFingerprint struct {
+	Fingerprint
+	pods []PodIdent
+}
+
+// NewTracingFingerprint returns a TracingFingerprint ready to accept up
+// to size pod identities without reallocating.
+func NewTracingFingerprint(size int) *TracingFingerprint {
+	return &TracingFingerprint{
+		Fingerprint: *NewFingerprint(size),
+		pods:        make([]PodIdent, 0, size),
+	}
+}
+
+// AddPod records the identity of pod in the fingerprint.
+func (tfp *TracingFingerprint) AddPod(pod PodIdentifier) {
+	tfp.Add(pod.GetNamespace(), pod.GetName())
+}
+
+// Add records the identity (namespace, name) in the fingerprint.
+func (tfp *TracingFingerprint) Add(namespace, name string) {
+	tfp.Fingerprint.Add(namespace, name)
+	tfp.pods = append(tfp.pods, PodIdent{Namespace: namespace, Name: name})
+}
+
+// RemovePod undoes a prior AddPod call for pod, keeping Status in sync
+// with the underlying signature; see Fingerprint.RemovePod.
+func (tfp *TracingFingerprint) RemovePod(pod PodIdentifier) {
+	tfp.Remove(pod.GetNamespace(), pod.GetName())
+}
+
+// Remove undoes a prior Add call for (namespace, name), keeping Status
+// in sync with the underlying signature; see Fingerprint.Remove.
+func (tfp *TracingFingerprint) Remove(namespace, name string) {
+	tfp.Fingerprint.Remove(namespace, name)
+	tfp.removePodIdent(PodIdent{Namespace: namespace, Name: name})
+}
+
+// removePodIdent drops a single occurrence of ident from pods, mirroring
+// the net-count semantics Fingerprint.Remove applies to the signature.
+func (tfp *TracingFingerprint) removePodIdent(ident PodIdent) {
+	for i, pod := range tfp.pods {
+		if pod == ident {
+			tfp.pods = append(tfp.pods[:i], tfp.pods[i+1:]...)
+			return
+		}
+	}
+}
+
+// Status returns a snapshot of the pods observed so far, sorted by
+// (namespace, name) so it can be compared byte-for-byte once persisted.
+func (tfp *TracingFingerprint) Status() Status {
+	sorted := make([]PodIdent, len(tfp.pods))
+	copy(sorted, tfp.pods)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return Status{
+		Version:   Version,
+		Signature: tfp.Sign(),
+		Pods:      sorted,
+	}
+}
+
+// WriteStatus persists status as indented JSON to the file at path, next
+// to wherever the caller already stores the plain signature, so it can
+// later be loaded back with ReadStatus or Compare.
+func WriteStatus(path string, status Status) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling fingerprint status: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ReadStatus decodes a Status previously written by WriteStatus.
+func ReadStatus(r io.Reader) (Status, error) {
+	var status Status
+	if err := json.NewDecoder(r).Decode(&status); err != nil {
+		return Status{}, fmt.Errorf("decoding fingerprint status: %w", err)
+	}
+	return status, nil
+}
+
+// Diff returns the symmetric difference between the pods recorded in a
+// and b: onlyInA holds the pods seen in a but not in b, and onlyInB the
+// converse. It is the primary tool for explaining an ErrSignatureMismatch
+// between two TracingFingerprints.
+func Diff(a, b Status) (onlyInA, onlyInB []PodIdent) {
+	inB := make(map[PodIdent]struct{}, len(b.Pods))
+	for _, pod := range b.Pods {
+		inB[pod] = struct{}{}
+	}
+	inA := make(map[PodIdent]struct{}, len(a.Pods))
+	for _, pod := range a.Pods {
+		inA[pod] = struct{}{}
+	}
+
+	for _, pod := range a.Pods {
+		if _, ok := inB[pod]; !ok {
+			onlyInA = append(onlyInA, pod)
+		}
+	}
+	for _, pod := range b.Pods {
+		if _, ok := inA[pod]; !ok {
+			onlyInB = append(onlyInB, pod)
+		}
+	}
+	return onlyInA, onlyInB
+}
+
+// Compare reads two Status values, as persisted by WriteStatus, from
+// statusA and statusB and returns their symmetric difference via Diff.
+func Compare(statusA, statusB io.Reader) (onlyInA, onlyInB []PodIdent, err error) {
+	a, err := ReadStatus(statusA)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading first status: %w", err)
+	}
+	b, err := ReadStatus(statusB)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading second status: %w", err)
+	}
+	onlyInA, onlyInB = Diff(a, b)
+	return onlyInA, onlyInB, nil
+}
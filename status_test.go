@@ -0,0 +1,127 @@
+/*
+ * Copyright 2022 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podfingerprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTracingFingerprintMatchesSignature(t *testing.T) {
+	tfp := NewTracingFingerprint(2)
+	tfp.Add("ns1", "pod1")
+	tfp.Add("ns2", "pod2")
+
+	fp := NewFingerprint(2)
+	fp.Add("ns1", "pod1")
+	fp.Add("ns2", "pod2")
+
+	if got, want := tfp.Sign(), fp.Sign(); got != want {
+		t.Fatalf("tracing fingerprint signature %q differs from plain one %q", got, want)
+	}
+
+	status := tfp.Status()
+	if status.Signature != tfp.Sign() {
+		t.Errorf("status signature %q does not match Sign() %q", status.Signature, tfp.Sign())
+	}
+	want := []PodIdent{{Namespace: "ns1", Name: "pod1"}, {Namespace: "ns2", Name: "pod2"}}
+	if !reflect.DeepEqual(status.Pods, want) {
+		t.Errorf("status pods got %+v expected %+v", status.Pods, want)
+	}
+}
+
+func TestTracingFingerprintRemoveStaysConsistent(t *testing.T) {
+	tfp := NewTracingFingerprint(2)
+	tfp.Add("ns1", "pod1")
+	tfp.Add("ns1", "pod2")
+
+	tfp.Remove("ns1", "pod2")
+
+	want := NewTracingFingerprint(1)
+	want.Add("ns1", "pod1")
+
+	status := tfp.Status()
+	if status.Signature != want.Sign() {
+		t.Fatalf("signature got %q expected %q", status.Signature, want.Sign())
+	}
+	wantPods := []PodIdent{{Namespace: "ns1", Name: "pod1"}}
+	if !reflect.DeepEqual(status.Pods, wantPods) {
+		t.Fatalf("Status.Pods got %+v expected %+v, disagreeing with Signature", status.Pods, wantPods)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tfpA := NewTracingFingerprint(3)
+	tfpA.Add("ns1", "pod1")
+	tfpA.Add("ns1", "pod2")
+	tfpA.Add("ns2", "pod3")
+
+	tfpB := NewTracingFingerprint(3)
+	tfpB.Add("ns1", "pod1")
+	tfpB.Add("ns2", "pod4")
+	tfpB.Add("ns2", "pod3")
+
+	onlyInA, onlyInB := Diff(tfpA.Status(), tfpB.Status())
+
+	wantOnlyInA := []PodIdent{{Namespace: "ns1", Name: "pod2"}}
+	wantOnlyInB := []PodIdent{{Namespace: "ns2", Name: "pod4"}}
+	if !reflect.DeepEqual(onlyInA, wantOnlyInA) {
+		t.Errorf("onlyInA got %+v expected %+v", onlyInA, wantOnlyInA)
+	}
+	if !reflect.DeepEqual(onlyInB, wantOnlyInB) {
+		t.Errorf("onlyInB got %+v expected %+v", onlyInB, wantOnlyInB)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tfpA := NewTracingFingerprint(1)
+	tfpA.Add("ns1", "pod1")
+	tfpB := NewTracingFingerprint(2)
+	tfpB.Add("ns1", "pod1")
+	tfpB.Add("ns1", "pod2")
+
+	var bufA, bufB bytes.Buffer
+	if err := WriteStatus("", Status{}); err == nil {
+		t.Fatalf("expected error writing status to an empty path")
+	}
+
+	encodeStatus(t, &bufA, tfpA.Status())
+	encodeStatus(t, &bufB, tfpB.Status())
+
+	onlyInA, onlyInB, err := Compare(&bufA, &bufB)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(onlyInA) != 0 {
+		t.Errorf("onlyInA got %+v expected none", onlyInA)
+	}
+	want := []PodIdent{{Namespace: "ns1", Name: "pod2"}}
+	if !reflect.DeepEqual(onlyInB, want) {
+		t.Errorf("onlyInB got %+v expected %+v", onlyInB, want)
+	}
+}
+
+func encodeStatus(t *testing.T, buf *bytes.Buffer, status Status) {
+	t.Helper()
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("marshaling status: %v", err)
+	}
+	buf.Write(data)
+}